@@ -0,0 +1,105 @@
+package ip17mon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// FindBatch looks up every ip in ips and writes the result into the
+// matching slot of out, which must have at least len(ips) elements. It
+// sorts a copy of the indices by IP value before doing the lookups so
+// consecutive binary searches land near each other in loc's index, then
+// fans the work out across GOMAXPROCS workers.
+func (loc *Locator) FindBatch(ips []uint32, out []*LocationInfo) {
+	n := len(ips)
+	if n == 0 {
+		return
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return ips[order[a]] < ips[order[b]] })
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	chunk := (n + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(positions []int) {
+			defer wg.Done()
+			for _, i := range positions {
+				out[i] = loc.FindByUint(ips[i])
+			}
+		}(order[start:end])
+	}
+	wg.Wait()
+}
+
+// EnrichOptions configures EnrichReader.
+type EnrichOptions struct {
+	// Extractor pulls the IP string and whatever else on the line should
+	// be carried through to the output (e.g. the remaining columns of a
+	// TSV row) out of one input line. A nil Extractor treats the whole
+	// line as the IP with nothing to carry through.
+	Extractor func(line []byte) (ip string, rest []byte)
+
+	// Format writes one output line given the extracted ip, the rest of
+	// the input line, and the resolved LocationInfo (nil if ip didn't
+	// parse). A nil Format writes a tab-separated
+	// "ip country region city isp [rest]" line.
+	Format func(w io.Writer, ip string, rest []byte, info *LocationInfo) error
+}
+
+// EnrichReader reads r one line at a time, resolves the IP on each line via
+// opts.Extractor, and writes an augmented line to w via opts.Format for
+// every line read. It streams rather than buffering the whole input, so it
+// can enrich arbitrarily large log files; for bulk jobs that already have
+// every IP in memory, FindBatch amortizes the lookup cost better.
+func (loc *Locator) EnrichReader(r io.Reader, w io.Writer, opts EnrichOptions) error {
+	extractor := opts.Extractor
+	if extractor == nil {
+		extractor = func(line []byte) (string, []byte) { return string(line), nil }
+	}
+	format := opts.Format
+	if format == nil {
+		format = defaultEnrichFormat
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		ip, rest := extractor(sc.Bytes())
+		info, _ := loc.Find(ip)
+		if err := format(w, ip, rest, info); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func defaultEnrichFormat(w io.Writer, ip string, rest []byte, info *LocationInfo) error {
+	country, region, city, isp := Null, Null, Null, Null
+	if info != nil {
+		country, region, city, isp = info.Country, info.Region, info.City, info.Isp
+	}
+
+	if len(rest) > 0 {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ip, country, region, city, isp, rest)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ip, country, region, city, isp)
+	return err
+}
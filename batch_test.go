@@ -0,0 +1,75 @@
+package ip17mon
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// buildTestLocator assembles a Locator directly from three adjacent
+// ranges (all under the first octet, so the IPv4 fast path's per-octet
+// index can be a single bucket), covering the FindByUint path FindBatch
+// relies on.
+func buildTestLocator() *Locator {
+	entries := []struct {
+		end     uint32
+		country string
+	}{
+		{99, "CN"},
+		{199, "US"},
+		{65535, "JP"},
+	}
+
+	var textData, rawIndex []byte
+	for _, e := range entries {
+		off := len(textData)
+		rec := []byte(e.country + "\tRegion\tCity\t")
+		textData = append(textData, rec...)
+		rawIndex = append(rawIndex,
+			byte(e.end>>24), byte(e.end>>16), byte(e.end>>8), byte(e.end),
+			byte(off), byte(off>>8), byte(off>>16),
+			byte(len(rec)),
+		)
+	}
+
+	index := make([]int, 256)
+	for i := range index {
+		index[i] = len(entries) - 1
+	}
+	index[0] = 0
+
+	return &Locator{rawIndex: rawIndex, nidx: len(entries), textData: textData, index: index}
+}
+
+func TestFindBatch(t *testing.T) {
+	loc := buildTestLocator()
+
+	ips := []uint32{150, 50, 40000, 99, 100}
+	want := []string{"US", "CN", "JP", "CN", "US"}
+
+	out := make([]*LocationInfo, len(ips))
+	loc.FindBatch(ips, out)
+
+	for i, info := range out {
+		if info == nil || info.Country != want[i] {
+			t.Errorf("out[%d] = %+v, want Country=%s", i, info, want[i])
+		}
+	}
+}
+
+func TestEnrichReader(t *testing.T) {
+	loc := buildTestLocator()
+
+	in := strings.NewReader("0.0.0.50\n0.0.0.150\nnot-an-ip\n")
+	var out bytes.Buffer
+	if err := loc.EnrichReader(in, &out, EnrichOptions{}); err != nil {
+		t.Fatalf("EnrichReader: %v", err)
+	}
+
+	want := "0.0.0.50\tCN\tRegion\tCity\tN/A\n" +
+		"0.0.0.150\tUS\tRegion\tCity\tN/A\n" +
+		"not-an-ip\tN/A\tN/A\tN/A\tN/A\n"
+	if got := out.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -7,63 +7,92 @@ import (
 	"io/ioutil"
 	"net"
 	"strconv"
-	"sync"
+	"sync/atomic"
 )
 
 const Null = "N/A"
 
 var (
 	ErrInvalidIp = errors.New("invalid ip format")
-	std          *Locator
-	switchMutex  sync.RWMutex
-	olddata      *Locator
-	newdata      *Locator
+
+	// std holds the default Locator. Find/FindByUint/etc. read it with no
+	// locking; Init/Reload/Watch publish to it with Store/Swap.
+	std atomic.Pointer[Locator]
+
+	// reloadHook, if set via SetReloadHook, is called after every
+	// successful Reload/ReloadMmap/Watch-triggered reload.
+	reloadHook atomic.Pointer[func(old, new *Locator)]
 )
 
 // Init defaut locator with dataFile
 func Init(dataFile string) (err error) {
-	switchMutex.Lock()
-	defer switchMutex.Unlock()
-
-	if std != nil {
+	if std.Load() != nil {
 		return
 	}
-	std, err = NewLocator(dataFile)
-	if err == nil {
-		olddata, newdata = std, std
+	loc, err := NewLocator(dataFile)
+	if err != nil {
+		return
 	}
+	std.Store(loc)
 	return
 }
 
-// Reload new data file
+// Reload new data file into the default Locator. The read path in
+// Find/FindByUint takes no lock, so in-flight lookups against the old
+// Locator keep running against its data until they return; nothing blocks
+// on the file read/parse this does.
 func Reload(dataFile string) (err error) {
-	switchMutex.Lock()
-	defer switchMutex.Unlock()
-
-	olddata, err = NewLocator(dataFile)
-	olddata, newdata = newdata, olddata
-	std = newdata
+	loc, err := NewLocator(dataFile)
+	if err != nil {
+		return
+	}
+	publish(loc)
 	return
 }
 
+// publish swaps in loc as the default Locator and fires the reload hook,
+// if one is set, with the outgoing and incoming Locator.
+func publish(loc *Locator) {
+	old := std.Swap(loc)
+	if hook := reloadHook.Load(); hook != nil {
+		(*hook)(old, loc)
+	}
+}
+
+// SetReloadHook registers fn to run after every successful reload of the
+// default Locator (via Reload, ReloadMmap, or Watch), receiving the
+// outgoing and incoming Locator. Since reloads no longer take a lock,
+// callers that need to release resources held by the outgoing Locator
+// (e.g. Close an mmap-backed one) are responsible for knowing when its
+// last reader has finished — fn is a convenient place to log or refcount
+// that, but it runs before any in-flight lookups against old necessarily
+// complete.
+func SetReloadHook(fn func(old, new *Locator)) {
+	if fn == nil {
+		reloadHook.Store(nil)
+		return
+	}
+	reloadHook.Store(&fn)
+}
+
 // Init defaut locator with data
 func InitWithData(data []byte) {
-	if std != nil {
+	if std.Load() != nil {
 		return
 	}
-	std = NewLocatorWithData(data)
+	std.Store(NewLocatorWithData(data))
 	return
 }
 
 // Find locationInfo by ip string
 // It will return err when ipstr is not a valid format
 func Find(ipstr string) (*LocationInfo, error) {
-	return std.Find(ipstr)
+	return std.Load().Find(ipstr)
 }
 
 // Find locationInfo by uint32
 func FindByUint(ip uint32) *LocationInfo {
-	return std.FindByUint(ip)
+	return std.Load().FindByUint(ip)
 }
 
 //-----------------------------------------------------------------------------
@@ -75,22 +104,45 @@ func NewLocator(dataFile string) (loc *Locator, err error) {
 		return
 	}
 	loc = NewLocatorWithData(data)
+	loc.tryLoadIPv6Companion(dataFile)
 	return
 }
 
 // New locator with data
 func NewLocatorWithData(data []byte) (loc *Locator) {
 	loc = new(Locator)
+	if p := sniffParser(data); p != nil {
+		idx, err := p.ParseIndex(data)
+		if err == nil {
+			loc.parser, loc.idx = p, idx
+			return
+		}
+	}
 	loc.init(data)
 	return
 }
 
 type Locator struct {
-	textData   []byte
-	indexData1 []uint32
-	indexData2 []int
-	indexData3 []int
-	index      []int
+	textData []byte
+	// rawIndex holds the nidx 8-byte index entries (4-byte big-endian
+	// inclusive range-end IP, 3-byte little-endian text offset, 1-byte text
+	// length) as a direct
+	// slice into the backing data, read on demand by indexIP/indexOffset/
+	// indexLen instead of being unpacked into three parallel slices.
+	rawIndex []byte
+	nidx     int
+	index    []int
+
+	ip6 *ip6Data
+
+	// parser/idx are set when data was recognised by a registered Parser
+	// (see parser.go) instead of the built-in 17monipdb format.
+	parser Parser
+	idx    Index
+
+	// mmapData is set when the backing data came from NewLocatorFromMmap;
+	// Close unmaps it.
+	mmapData []byte
 }
 
 type LocationInfo struct {
@@ -113,39 +165,64 @@ func (loc *Locator) Find(ipstr string) (info *LocationInfo, err error) {
 		err = ErrInvalidIp
 		return
 	}
-	info = loc.FindByUint(binary.BigEndian.Uint32([]byte(ip.To4())))
-	return
+	return loc.FindByIP(ip)
 }
 
 // Find locationInfo by uint32
 func (loc *Locator) FindByUint(ip uint32) (info *LocationInfo) {
-	end := len(loc.indexData1) - 1
+	if loc.idx != nil {
+		data, off, ok := loc.idx.Lookup(ip)
+		if !ok {
+			return nil
+		}
+		info, _ = loc.parser.ParseRecord(data, off)
+		if info != nil {
+			fillNullFields(info)
+		}
+		return
+	}
+
+	end := loc.nidx - 1
 	if ip>>24 != 0xff {
 		end = loc.index[(ip>>24)+1]
 	}
 	idx := loc.findIndexOffset(ip, loc.index[ip>>24], end)
-	off := loc.indexData2[idx]
-	return newLocationInfo(loc.textData[off : off+loc.indexData3[idx]])
+	off := loc.indexOffset(idx)
+	return newLocationInfo(loc.textData[off : off+loc.indexLen(idx)])
 }
 
 // binary search
 func (loc *Locator) findIndexOffset(ip uint32, start, end int) int {
 	for start < end {
 		mid := (start + end) / 2
-		if ip > loc.indexData1[mid] {
+		if ip > loc.indexIP(mid) {
 			start = mid + 1
 		} else {
 			end = mid
 		}
 	}
 
-	if loc.indexData1[end] >= ip {
+	if loc.indexIP(end) >= ip {
 		return end
 	}
 
 	return start
 }
 
+func (loc *Locator) indexIP(i int) uint32 {
+	e := loc.rawIndex[i*8 : i*8+8]
+	return binary.BigEndian.Uint32(e[:4])
+}
+
+func (loc *Locator) indexOffset(i int) int {
+	e := loc.rawIndex[i*8 : i*8+8]
+	return int(uint32(e[4]) | uint32(e[5])<<8 | uint32(e[6])<<16)
+}
+
+func (loc *Locator) indexLen(i int) int {
+	return int(loc.rawIndex[i*8+7])
+}
+
 func (loc *Locator) init(data []byte) {
 	textoff := int(binary.BigEndian.Uint32(data[:4]))
 
@@ -157,18 +234,8 @@ func (loc *Locator) init(data []byte) {
 		loc.index[i] = int(binary.LittleEndian.Uint32(data[off : off+4]))
 	}
 
-	nidx := (textoff - 4 - 1024 - 1024) / 8
-
-	loc.indexData1 = make([]uint32, nidx)
-	loc.indexData2 = make([]int, nidx)
-	loc.indexData3 = make([]int, nidx)
-
-	for i := 0; i < nidx; i++ {
-		off := 4 + 1024 + i*8
-		loc.indexData1[i] = binary.BigEndian.Uint32(data[off : off+4])
-		loc.indexData2[i] = int(uint32(data[off+4]) | uint32(data[off+5])<<8 | uint32(data[off+6])<<16)
-		loc.indexData3[i] = int(data[off+7])
-	}
+	loc.nidx = (textoff - 4 - 1024 - 1024) / 8
+	loc.rawIndex = data[4+1024 : 4+1024+loc.nidx*8]
 	return
 }
 
@@ -215,6 +282,15 @@ func newLocationInfo(str []byte) *LocationInfo {
 		panic("unexpected ip info:" + string(str))
 	}
 
+	fillNullFields(info)
+	return info
+}
+
+// fillNullFields replaces any empty Country/Region/City/Isp with Null, so
+// callers see a consistent placeholder regardless of whether info came
+// from the built-in 17monipdb decoder above or a registered Parser (see
+// parser.go) whose own record format leaves some fields empty.
+func fillNullFields(info *LocationInfo) {
 	if len(info.Country) == 0 {
 		info.Country = Null
 	}
@@ -227,5 +303,4 @@ func newLocationInfo(str []byte) *LocationInfo {
 	if len(info.Isp) == 0 {
 		info.Isp = Null
 	}
-	return info
 }
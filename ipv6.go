@@ -0,0 +1,164 @@
+package ip17mon
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+)
+
+// ErrNoIPv6Data is returned when an IPv6 lookup is attempted against a
+// Locator that has no IPv6 index loaded.
+var ErrNoIPv6Data = errors.New("no ipv6 data loaded")
+
+// ip6Data holds the IPv6 equivalent of Locator's textData/rawIndex/index
+// fields. Ranges are addressed with their top 16 bits (the /16) pre-indexed
+// the same way the IPv4 path pre-indexes on the first octet, so the binary
+// search only ever scans within one /16 block.
+type ip6Data struct {
+	textData   []byte
+	indexData1 []uint64 // inclusive range end, high 64 bits
+	indexData2 []uint64 // inclusive range end, low 64 bits
+	indexData3 []int    // offset into textData
+	indexData4 []int    // record length
+	index      []int    // 65536-entry /16 index into indexData1/2
+}
+
+// LoadIPv6 reads an ipv6wry-style companion data file and attaches it to
+// loc, enabling FindByIP/FindByUint128 to resolve IPv6 addresses. The file
+// layout mirrors the IPv4 format: a 4 byte text offset, a 65536-entry
+// (4 byte) /16 index, then 20-byte records of (hi uint64, lo uint64, 3-byte
+// text offset, 1-byte text length).
+func (loc *Locator) LoadIPv6(dataFile string) error {
+	data, err := ioutil.ReadFile(dataFile)
+	if err != nil {
+		return err
+	}
+	return loc.loadIPv6Data(data)
+}
+
+func (loc *Locator) loadIPv6Data(data []byte) error {
+	d := new(ip6Data)
+
+	textoff := int(binary.BigEndian.Uint32(data[:4]))
+	d.textData = data[textoff:]
+
+	const nseg = 65536
+	d.index = make([]int, nseg)
+	for i := 0; i < nseg; i++ {
+		off := 4 + i*4
+		d.index[i] = int(binary.LittleEndian.Uint32(data[off : off+4]))
+	}
+
+	base := 4 + nseg*4
+	nidx := (textoff - base) / 20
+	d.indexData1 = make([]uint64, nidx)
+	d.indexData2 = make([]uint64, nidx)
+	d.indexData3 = make([]int, nidx)
+	d.indexData4 = make([]int, nidx)
+
+	for i := 0; i < nidx; i++ {
+		off := base + i*20
+		d.indexData1[i] = binary.BigEndian.Uint64(data[off : off+8])
+		d.indexData2[i] = binary.BigEndian.Uint64(data[off+8 : off+16])
+		e := data[off+16 : off+20]
+		d.indexData3[i] = int(uint32(e[0]) | uint32(e[1])<<8 | uint32(e[2])<<16)
+		d.indexData4[i] = int(e[3])
+	}
+
+	loc.ip6 = d
+	return nil
+}
+
+// tryLoadIPv6Companion looks for "<dataFile>6" next to dataFile (the
+// convention used for ipv6wry companions to 17monipdb files) and loads it
+// if present. A missing companion file is not an error: IPv4-only databases
+// are the common case.
+func (loc *Locator) tryLoadIPv6Companion(dataFile string) {
+	companion := dataFile + "6"
+	if _, err := os.Stat(companion); err != nil {
+		return
+	}
+	loc.LoadIPv6(companion)
+}
+
+// FindByIP looks up location info for ip, dispatching on address family.
+// IPv4 and IPv4-mapped IPv6 addresses use the existing first-octet fast path;
+// native IPv6 addresses use the IPv6 index loaded via LoadIPv6, if any.
+func (loc *Locator) FindByIP(ip net.IP) (info *LocationInfo, err error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		info = loc.FindByUint(binary.BigEndian.Uint32(ip4))
+		return
+	}
+
+	ip16 := ip.To16()
+	if ip16 == nil {
+		err = ErrInvalidIp
+		return
+	}
+	if loc.ip6 == nil {
+		err = ErrNoIPv6Data
+		return
+	}
+
+	hi := binary.BigEndian.Uint64(ip16[:8])
+	lo := binary.BigEndian.Uint64(ip16[8:])
+	info = loc.FindByUint128(hi, lo)
+	return
+}
+
+// FindByUint128 looks up location info for an IPv6 address given as two
+// big-endian halves: hi covers bytes 0-7, lo covers bytes 8-15.
+func (loc *Locator) FindByUint128(hi, lo uint64) *LocationInfo {
+	d := loc.ip6
+	if d == nil {
+		return nil
+	}
+
+	seg := int(hi >> 48)
+	end := len(d.indexData1) - 1
+	if seg != 0xffff {
+		end = d.index[seg+1]
+	}
+	idx := loc.findIP6IndexOffset(hi, lo, d.index[seg], end)
+	off := d.indexData3[idx]
+	return newLocationInfo(d.textData[off : off+d.indexData4[idx]])
+}
+
+// findIP6IndexOffset mirrors findIndexOffset's binary search over a
+// 128-bit-keyed index.
+func (loc *Locator) findIP6IndexOffset(hi, lo uint64, start, end int) int {
+	d := loc.ip6
+	for start < end {
+		mid := (start + end) / 2
+		if uint128Greater(hi, lo, d.indexData1[mid], d.indexData2[mid]) {
+			start = mid + 1
+		} else {
+			end = mid
+		}
+	}
+
+	if !uint128Greater(hi, lo, d.indexData1[end], d.indexData2[end]) {
+		return end
+	}
+	return start
+}
+
+func uint128Greater(hi1, lo1, hi2, lo2 uint64) bool {
+	if hi1 != hi2 {
+		return hi1 > hi2
+	}
+	return lo1 > lo2
+}
+
+// FindByIP looks up location info for ip using the default Locator.
+func FindByIP(ip net.IP) (*LocationInfo, error) {
+	return std.Load().FindByIP(ip)
+}
+
+// FindByUint128 looks up location info for an IPv6 address using the
+// default Locator.
+func FindByUint128(hi, lo uint64) *LocationInfo {
+	return std.Load().FindByUint128(hi, lo)
+}
@@ -0,0 +1,88 @@
+package ip17mon
+
+import "testing"
+
+// buildIPv6Companion assembles a minimal ipv6wry-style companion file: a
+// 4-byte text offset, a full 65536-entry /16 index (only segment 0 is
+// populated; FindByUint128 only consults it for addresses whose top 16
+// bits are 0), and nidx 20-byte records in ascending lo order, each naming
+// the inclusive upper bound of its range (mirroring the IPv4 index's
+// end-of-range convention).
+func buildIPv6Companion(t *testing.T, ranges []struct {
+	endLo   uint64
+	country string
+}) []byte {
+	t.Helper()
+
+	const nseg = 65536
+	index := make([]byte, nseg*4)
+	putUint32LE := func(b []byte, v uint32) {
+		b[0], b[1], b[2], b[3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+	}
+	putUint32LE(index[0*4:1*4], 0)                     // index[0]: segment 0 starts at record 0
+	putUint32LE(index[1*4:2*4], uint32(len(ranges)-1)) // index[1]: segment 0 ends at the last record
+
+	var records, textData []byte
+	for _, r := range ranges {
+		off := len(textData)
+		rec := []byte(r.country + "\tRegion\tCity\t")
+		textData = append(textData, rec...)
+
+		var rb [20]byte
+		// hi is left 0 for every record: all test addresses live in
+		// segment 0 (top 16 bits zero).
+		for i := 0; i < 8; i++ {
+			rb[8+i] = byte(r.endLo >> uint(8*(7-i)))
+		}
+		rb[16], rb[17], rb[18] = byte(off), byte(off>>8), byte(off>>16)
+		rb[19] = byte(len(rec))
+		records = append(records, rb[:]...)
+	}
+
+	textoff := 4 + len(index) + len(records)
+	// textoff is big-endian, per loadIPv6Data's read of data[:4].
+	data := []byte{byte(textoff >> 24), byte(textoff >> 16), byte(textoff >> 8), byte(textoff)}
+	data = append(data, index...)
+	data = append(data, records...)
+	data = append(data, textData...)
+	return data
+}
+
+// TestLoadIPv6DataAndFindByUint128 exercises FindByUint128 across range
+// boundaries, including the exact last address of one range and the exact
+// first address of the next.
+func TestLoadIPv6DataAndFindByUint128(t *testing.T) {
+	data := buildIPv6Companion(t, []struct {
+		endLo   uint64
+		country string
+	}{
+		{99, "CN"},
+		{199, "US"},
+		{1000000, "JP"},
+	})
+
+	loc := new(Locator)
+	if err := loc.loadIPv6Data(data); err != nil {
+		t.Fatalf("loadIPv6Data: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		lo   uint64
+		want string
+	}{
+		{"mid first range", 50, "CN"},
+		{"last address of first range", 99, "CN"},
+		{"first address of second range", 100, "US"},
+		{"mid second range", 150, "US"},
+		{"mid third range", 500000, "JP"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info := loc.FindByUint128(0, c.lo)
+			if info.Country != c.want {
+				t.Errorf("Country = %q, want %q", info.Country, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package ip17mon
+
+import (
+	"os"
+	"syscall"
+)
+
+// NewLocatorFromMmap memory-maps dataFile read-only instead of reading it
+// into the heap, so textData and rawIndex end up referencing the mapped
+// region directly; the 256-entry first-octet index is still decoded into a
+// heap-allocated slice. Call Close when done with the returned Locator to unmap
+// it; a process that never reloads can skip that and let exit tear it down.
+func NewLocatorFromMmap(path string) (loc *Locator, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	size := int(fi.Size())
+	if size == 0 {
+		return nil, syscall.EINVAL
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return
+	}
+
+	loc = NewLocatorWithData(data)
+	loc.mmapData = data
+	loc.tryLoadIPv6Companion(path)
+	return
+}
+
+// Close unmaps the memory backing loc if it was created via
+// NewLocatorFromMmap. It is a no-op for Locators built from a plain []byte.
+func (loc *Locator) Close() error {
+	if loc.mmapData == nil {
+		return nil
+	}
+	err := syscall.Munmap(loc.mmapData)
+	loc.mmapData = nil
+	return err
+}
+
+// ReloadMmap swaps in a Locator memory-mapped from dataFile as the default
+// Locator. It does not Close the outgoing Locator itself — see
+// SetReloadHook for why — so callers that mmap their data file should
+// arrange to Close the old Locator once they know its last reader is done
+// with it.
+func ReloadMmap(dataFile string) (err error) {
+	loc, err := NewLocatorFromMmap(dataFile)
+	if err != nil {
+		return
+	}
+	publish(loc)
+	return
+}
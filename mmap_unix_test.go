@@ -0,0 +1,38 @@
+//go:build !windows
+
+package ip17mon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLocatorFromMmapAndClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "17monipdb.dat")
+	if err := os.WriteFile(path, buildLocatorFile("CN"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loc, err := NewLocatorFromMmap(path)
+	if err != nil {
+		t.Fatalf("NewLocatorFromMmap: %v", err)
+	}
+
+	info, err := loc.Find("1.2.3.4")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if info.Country != "CN" {
+		t.Errorf("Country = %q, want %q", info.Country, "CN")
+	}
+
+	if err := loc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// Close is a no-op past the first call.
+	if err := loc.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
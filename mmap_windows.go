@@ -0,0 +1,21 @@
+//go:build windows
+
+package ip17mon
+
+import "errors"
+
+// ErrMmapUnsupported is returned by NewLocatorFromMmap/ReloadMmap on
+// platforms where this package has no mmap implementation yet.
+var ErrMmapUnsupported = errors.New("ip17mon: mmap loader not supported on this platform")
+
+func NewLocatorFromMmap(path string) (*Locator, error) {
+	return nil, ErrMmapUnsupported
+}
+
+func (loc *Locator) Close() error {
+	return nil
+}
+
+func ReloadMmap(dataFile string) error {
+	return ErrMmapUnsupported
+}
@@ -0,0 +1,62 @@
+package ip17mon
+
+import "sync"
+
+// Index is the in-memory range index produced by a Parser. Lookup returns
+// the backing buffer for ip's record plus the offset within it where the
+// record begins, so ParseRecord can resolve further offsets the format
+// stores relative to that same buffer (e.g. qqwry's redirect records,
+// which may point anywhere in the file, not just forward from the record
+// itself). ok is false when ip falls outside any range in the database.
+type Index interface {
+	Lookup(ip uint32) (data []byte, offset int, ok bool)
+}
+
+// Parser understands one on-disk database format. Sniff identifies the
+// format from a file's header/magic bytes, ParseIndex builds the range
+// index once at load time, and ParseRecord decodes the record starting at
+// offset within data into a LocationInfo. Format subpackages (e.g.
+// ip17mon/parsers/qqwry) register an implementation via RegisterParser.
+type Parser interface {
+	Sniff(data []byte) bool
+	ParseIndex(data []byte) (Index, error)
+	ParseRecord(data []byte, offset int) (*LocationInfo, error)
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]Parser{}
+	// parserOrder records registration order so sniffParser tries parsers
+	// deterministically instead of in Go's randomized map iteration order.
+	// RegisterParser is normally only called from format subpackages'
+	// init() functions, in whatever order they're imported, so in practice
+	// this order is fixed per-binary; it's what makes which parser wins a
+	// Sniff tie reproducible across runs of the same program.
+	parserOrder []string
+)
+
+// RegisterParser makes a Parser available to NewLocator/NewLocatorWithData
+// under name, which must be unique. It is meant to be called from a format
+// subpackage's init().
+func RegisterParser(name string, p Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	if _, exists := parsers[name]; !exists {
+		parserOrder = append(parserOrder, name)
+	}
+	parsers[name] = p
+}
+
+// sniffParser returns the first registered Parser (in registration order)
+// that claims data, or nil if none do, in which case the caller falls back
+// to the built-in 17monipdb format.
+func sniffParser(data []byte) Parser {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	for _, name := range parserOrder {
+		if p := parsers[name]; p.Sniff(data) {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package ip17mon
+
+import "testing"
+
+type stubParser struct {
+	name  string
+	sniff bool
+}
+
+func (s stubParser) Sniff(data []byte) bool              { return s.sniff }
+func (stubParser) ParseIndex(data []byte) (Index, error) { return nil, nil }
+func (stubParser) ParseRecord(data []byte, offset int) (*LocationInfo, error) {
+	return nil, nil
+}
+
+// TestSniffParserOrder verifies sniffParser tries parsers in registration
+// order rather than Go's randomized map order, so two parsers that both
+// claim the same file resolve to the same winner every run.
+func TestSniffParserOrder(t *testing.T) {
+	parsersMu.Lock()
+	savedParsers, savedOrder := parsers, parserOrder
+	parsers, parserOrder = map[string]Parser{}, nil
+	parsersMu.Unlock()
+	defer func() {
+		parsersMu.Lock()
+		parsers, parserOrder = savedParsers, savedOrder
+		parsersMu.Unlock()
+	}()
+
+	RegisterParser("z-first", stubParser{name: "z-first", sniff: true})
+	RegisterParser("a-second", stubParser{name: "a-second", sniff: true})
+
+	for i := 0; i < 20; i++ {
+		p := sniffParser(nil)
+		sp, ok := p.(stubParser)
+		if !ok || sp.name != "z-first" {
+			t.Fatalf("sniffParser = %+v, want the first-registered parser (z-first)", p)
+		}
+	}
+
+	parsersMu.RLock()
+	order := append([]string(nil), parserOrder...)
+	parsersMu.RUnlock()
+	if len(order) != 2 || order[0] != "z-first" || order[1] != "a-second" {
+		t.Errorf("parserOrder = %v, want [z-first a-second]", order)
+	}
+}
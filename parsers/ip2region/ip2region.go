@@ -0,0 +1,108 @@
+// Package ip2region parses the ip2region.db binary format (v1.0: an 8-byte
+// super block pointing at a run of fixed-width start/end-IP index entries) and
+// registers itself with ip17mon under the name "ip2region".
+package ip2region
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/xiaoqiang0/ip17mon"
+)
+
+func init() {
+	ip17mon.RegisterParser("ip2region", parser{})
+}
+
+const (
+	indexEntrySize = 12 // startIP(4) + endIP(4) + dataPtr(3) + dataLen(1)
+	superBlockSize = 8  // startIndexPtr(4) + endIndexPtr(4)
+)
+
+var errBadHeader = errors.New("ip2region: malformed header")
+
+type parser struct{}
+
+// Sniff has no magic bytes to go on either: it accepts the format when the
+// super block's start/end index pointers are internally consistent.
+func (parser) Sniff(data []byte) bool {
+	if len(data) < superBlockSize {
+		return false
+	}
+	start := binary.LittleEndian.Uint32(data[0:4])
+	end := binary.LittleEndian.Uint32(data[4:8])
+	return end >= start && (end-start)%indexEntrySize == 0 && int(end)+indexEntrySize <= len(data)
+}
+
+type index struct {
+	data  []byte
+	start uint32
+	n     int
+}
+
+func (parser) ParseIndex(data []byte) (ip17mon.Index, error) {
+	if len(data) < superBlockSize {
+		return nil, errBadHeader
+	}
+	start := binary.LittleEndian.Uint32(data[0:4])
+	end := binary.LittleEndian.Uint32(data[4:8])
+	return &index{data: data, start: start, n: int((end-start)/indexEntrySize) + 1}, nil
+}
+
+func (idx *index) entry(i int) (startIP, endIP uint32, dataOff uint32, dataLen int) {
+	off := idx.start + uint32(i)*indexEntrySize
+	e := idx.data[off : off+indexEntrySize]
+	startIP = binary.LittleEndian.Uint32(e[0:4])
+	endIP = binary.LittleEndian.Uint32(e[4:8])
+	dataOff = uint32(e[8]) | uint32(e[9])<<8 | uint32(e[10])<<16
+	dataLen = int(e[11])
+	return
+}
+
+// Lookup binary searches the start/end-IP index and returns the "region"
+// string record (pipe-delimited country|region|province|city|isp) it
+// points at. ip2region records are self-contained (no redirects), so the
+// returned data is already trimmed to exactly that record and offset is 0.
+func (idx *index) Lookup(ip uint32) (data []byte, offset int, ok bool) {
+	lo, hi := 0, idx.n-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		startIP, endIP, dataOff, dataLen := idx.entry(mid)
+		switch {
+		case ip < startIP:
+			hi = mid - 1
+		case ip > endIP:
+			lo = mid + 1
+		default:
+			if int(dataOff)+dataLen > len(idx.data) {
+				return nil, 0, false
+			}
+			return idx.data[dataOff : dataOff+uint32(dataLen)], 0, true
+		}
+	}
+	return nil, 0, false
+}
+
+// ParseRecord splits the pipe-delimited "country|region|province|city|isp"
+// record ip2region stores per range. Missing fields are ip2region's own
+// "0" placeholder, which we normalise away and let the caller substitute
+// ip17mon.Null for.
+func (parser) ParseRecord(data []byte, offset int) (*ip17mon.LocationInfo, error) {
+	fields := strings.Split(string(data[offset:]), "|")
+	if len(fields) < 5 {
+		return nil, errors.New("ip2region: malformed record")
+	}
+	get := func(i int) string {
+		if fields[i] == "0" {
+			return ""
+		}
+		return fields[i]
+	}
+	return &ip17mon.LocationInfo{
+		Country: get(0),
+		Region:  get(2),
+		City:    get(3),
+		Isp:     get(4),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package ip2region
+
+import "testing"
+
+// buildIndex assembles a minimal ip2region buffer: an 8-byte super block
+// pointing at a single index entry, followed by its pipe-delimited region
+// record.
+func buildIndex(t *testing.T, startIP, endIP uint32, region string) []byte {
+	t.Helper()
+
+	data := make([]byte, superBlockSize)
+	recordOff := len(data) + indexEntrySize
+	record := []byte(region)
+
+	putUint32LE := func(v uint32) []byte {
+		return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+	}
+	copy(data[0:4], putUint32LE(uint32(len(data))))
+	copy(data[4:8], putUint32LE(uint32(len(data))))
+
+	entry := append(putUint32LE(startIP), putUint32LE(endIP)...)
+	entry = append(entry, byte(recordOff), byte(recordOff>>8), byte(recordOff>>16), byte(len(record)))
+	data = append(data, entry...)
+	data = append(data, record...)
+	return data
+}
+
+func TestSniffAndLookup(t *testing.T) {
+	data := buildIndex(t, 100, 200, "China|0|0|Beijing|Telecom")
+
+	p := parser{}
+	if !p.Sniff(data) {
+		t.Fatal("Sniff returned false for well-formed data")
+	}
+
+	idx, err := p.ParseIndex(data)
+	if err != nil {
+		t.Fatalf("ParseIndex: %v", err)
+	}
+
+	recData, off, ok := idx.Lookup(150)
+	if !ok {
+		t.Fatal("Lookup(150) = not ok, want a match in [100, 200]")
+	}
+
+	info, err := p.ParseRecord(recData, off)
+	if err != nil {
+		t.Fatalf("ParseRecord: %v", err)
+	}
+	if info.Country != "China" || info.Region != "" || info.City != "Beijing" || info.Isp != "Telecom" {
+		t.Errorf("got %+v, want Country=China Region=\"\" City=Beijing Isp=Telecom", info)
+	}
+
+	if _, _, ok := idx.Lookup(201); ok {
+		t.Error("Lookup(201) = ok, want no match outside [100, 200]")
+	}
+}
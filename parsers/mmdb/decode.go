@@ -0,0 +1,196 @@
+package mmdb
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Data-section type IDs, per the MaxMind DB format spec.
+const (
+	typePointer = 1
+	typeString  = 2
+	typeDouble  = 3
+	typeBytes   = 4
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeUint128 = 10
+	typeArray   = 11
+	typeBoolean = 14
+	typeFloat   = 15
+)
+
+var errTruncated = errors.New("mmdb: truncated data")
+
+// decode reads one data-section value starting at offset and returns it
+// plus the offset immediately following it. Maps decode to
+// map[string]interface{}, arrays to []interface{}, pointers are followed
+// transparently and return the pointed-to value (with next still pointing
+// past the pointer itself, per spec).
+func decode(data []byte, offset int) (interface{}, int, error) {
+	if offset >= len(data) {
+		return nil, 0, errTruncated
+	}
+	ctrl := data[offset]
+	offset++
+	typeID := int(ctrl >> 5)
+	if typeID == 0 {
+		if offset >= len(data) {
+			return nil, 0, errTruncated
+		}
+		typeID = 7 + int(data[offset])
+		offset++
+	}
+
+	if typeID == typePointer {
+		return decodePointer(data, offset, ctrl)
+	}
+	if typeID == typeBoolean {
+		return (ctrl & 0x1f) != 0, offset, nil
+	}
+
+	size, offset, err := decodeSize(data, offset, ctrl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// For maps and arrays, size is an element count, not a byte length —
+	// their members start right after the (possibly multi-byte) size
+	// encoding, with no fixed-width payload to slice off first.
+	switch typeID {
+	case typeMap:
+		return decodeMap(data, offset, size)
+	case typeArray:
+		return decodeArray(data, offset, size)
+	}
+
+	if offset+size > len(data) {
+		return nil, 0, errTruncated
+	}
+	payload := data[offset : offset+size]
+	next := offset + size
+
+	switch typeID {
+	case typeString:
+		return string(payload), next, nil
+	case typeUint16:
+		return uint16(beUintN(payload)), next, nil
+	case typeUint32:
+		return uint32(beUintN(payload)), next, nil
+	case typeUint64:
+		return beUintN(payload), next, nil
+	case typeInt32:
+		return int32(beUintN(payload)), next, nil
+	case typeBytes, typeDouble, typeFloat, typeUint128:
+		// Not needed for the country/region/city/isp fields ip17mon pulls
+		// out of a record; returned as raw bytes for callers that care.
+		return payload, next, nil
+	default:
+		return nil, next, nil
+	}
+}
+
+func decodeSize(data []byte, offset int, ctrl byte) (int, int, error) {
+	size := int(ctrl & 0x1f)
+	switch {
+	case size < 29:
+		return size, offset, nil
+	case size == 29:
+		if offset >= len(data) {
+			return 0, 0, errTruncated
+		}
+		return 29 + int(data[offset]), offset + 1, nil
+	case size == 30:
+		if offset+2 > len(data) {
+			return 0, 0, errTruncated
+		}
+		return 29 + 256 + int(binary.BigEndian.Uint16(data[offset:offset+2])), offset + 2, nil
+	default: // 31
+		if offset+3 > len(data) {
+			return 0, 0, errTruncated
+		}
+		v := int(data[offset])<<16 | int(data[offset+1])<<8 | int(data[offset+2])
+		return 29 + 256 + 65536 + v, offset + 3, nil
+	}
+}
+
+// decodePointer resolves a pointer value and decodes the value it points
+// to; next is the offset immediately after the pointer's own bytes (not
+// after the pointed-to value), per spec.
+func decodePointer(data []byte, offset int, ctrl byte) (interface{}, int, error) {
+	size := (ctrl & 0x18) >> 3
+	var ptr int
+	var next int
+	switch size {
+	case 0:
+		if offset >= len(data) {
+			return nil, 0, errTruncated
+		}
+		ptr = int(ctrl&0x07)<<8 | int(data[offset])
+		next = offset + 1
+	case 1:
+		if offset+2 > len(data) {
+			return nil, 0, errTruncated
+		}
+		ptr = int(ctrl&0x07)<<16 | int(data[offset])<<8 | int(data[offset+1])
+		ptr += 2048
+		next = offset + 2
+	case 2:
+		if offset+3 > len(data) {
+			return nil, 0, errTruncated
+		}
+		ptr = int(ctrl&0x07)<<24 | int(data[offset])<<16 | int(data[offset+1])<<8 | int(data[offset+2])
+		ptr += 2048 + 524288
+		next = offset + 3
+	default: // 3
+		if offset+4 > len(data) {
+			return nil, 0, errTruncated
+		}
+		ptr = int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		next = offset + 4
+	}
+
+	v, _, err := decode(data, ptr)
+	return v, next, err
+}
+
+func decodeMap(data []byte, offset, count int) (map[string]interface{}, int, error) {
+	m := make(map[string]interface{}, count)
+	for i := 0; i < count; i++ {
+		key, next, err := decode(data, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		ks, _ := key.(string)
+		val, next2, err := decode(data, next)
+		if err != nil {
+			return nil, 0, err
+		}
+		m[ks] = val
+		offset = next2
+	}
+	return m, offset, nil
+}
+
+func decodeArray(data []byte, offset, count int) ([]interface{}, int, error) {
+	arr := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		val, next, err := decode(data, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		arr = append(arr, val)
+		offset = next
+	}
+	return arr, offset, nil
+}
+
+func beUintN(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,49 @@
+package mmdb
+
+import "testing"
+
+func TestDecodeStringAndMap(t *testing.T) {
+	// map{"iso_code": "CN"}: control 0xe1 (type 7=map, size 1), key
+	// control 0x48 (string, size 8) "iso_code", value control 0x42
+	// (string, size 2) "CN"; easiest to build with a tiny literal buffer.
+	data := []byte{
+		0xe1,                                         // map, size 1
+		0x48, 'i', 's', 'o', '_', 'c', 'o', 'd', 'e', // string, size 8
+		0x42, 'C', 'N', // string, size 2
+	}
+
+	v, next, err := decode(data, 0)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if next != len(data) {
+		t.Errorf("next = %d, want %d", next, len(data))
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("decoded value is %T, want map[string]interface{}", v)
+	}
+	if got, want := m["iso_code"], "CN"; got != want {
+		t.Errorf("iso_code = %v, want %v", got, want)
+	}
+}
+
+func TestDecodePointer(t *testing.T) {
+	// Byte 0: pointer (type 1), size class 0, high 3 bits of value = 0.
+	// Points at offset 2, where a 2-byte string "hi" lives.
+	data := []byte{
+		0x20, 0x02, // pointer -> offset 2
+		0x42, 'h', 'i', // string, size 2
+	}
+
+	v, next, err := decode(data, 0)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if next != 2 {
+		t.Errorf("next = %d, want 2 (just past the pointer's own bytes)", next)
+	}
+	if got, want := v.(string), "hi"; got != want {
+		t.Errorf("pointed-to value = %q, want %q", got, want)
+	}
+}
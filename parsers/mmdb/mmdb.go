@@ -0,0 +1,241 @@
+// Package mmdb parses MaxMind DB (.mmdb) files — the format behind
+// GeoLite2/GeoIP2 — and registers itself with ip17mon under the name
+// "mmdb". Only the subset of the data-section type system needed to pull
+// country/region/city/isp strings out of a GeoIP2-City-shaped record is
+// implemented; array-valued fields other than the first element (e.g.
+// additional subdivisions) are ignored.
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/xiaoqiang0/ip17mon"
+)
+
+func init() {
+	ip17mon.RegisterParser("mmdb", parser{})
+}
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// metadataSearchWindow bounds how far from the end of the file we scan for
+// metadataMarker, per the MaxMind DB spec ("search within the last 128KiB").
+const metadataSearchWindow = 128 * 1024
+
+var (
+	errNoMetadata = errors.New("mmdb: metadata marker not found")
+	errBadData    = errors.New("mmdb: malformed data section")
+)
+
+type parser struct{}
+
+func (parser) Sniff(data []byte) bool {
+	return findMetadata(data) >= 0
+}
+
+func findMetadata(data []byte) int {
+	from := 0
+	if len(data) > metadataSearchWindow {
+		from = len(data) - metadataSearchWindow
+	}
+	i := bytes.LastIndex(data[from:], metadataMarker)
+	if i < 0 {
+		return -1
+	}
+	return from + i + len(metadataMarker)
+}
+
+type index struct {
+	data       []byte
+	treeOffset int
+	dataOffset int
+	nodeCount  int
+	recordSize int // bits per record; each node is 2 records
+	ipVersion  int
+}
+
+func (parser) ParseIndex(data []byte) (ip17mon.Index, error) {
+	off := findMetadata(data)
+	if off < 0 {
+		return nil, errNoMetadata
+	}
+	meta, _, err := decode(data, off)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errBadData
+	}
+
+	nodeCount := toInt(m["node_count"])
+	recordSize := toInt(m["record_size"])
+	ipVersion := toInt(m["ip_version"])
+	if nodeCount == 0 || recordSize == 0 {
+		return nil, errBadData
+	}
+
+	treeSize := nodeCount * recordSize * 2 / 8
+	return &index{
+		data:       data,
+		treeOffset: 0,
+		dataOffset: treeSize + 16, // 16-byte all-zero separator after the tree
+		nodeCount:  nodeCount,
+		recordSize: recordSize,
+		ipVersion:  ipVersion,
+	}, nil
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	case uint64:
+		return int(n)
+	case int32:
+		return int(n)
+	}
+	return 0
+}
+
+// Lookup walks the binary search tree bit-by-bit over ip's 32 bits (IPv6
+// databases hold IPv4 addresses under ::/96 of the full 128-bit tree, per
+// spec, so we offset into the tree accordingly for ip_version 6).
+func (idx *index) Lookup(ip uint32) (data []byte, offset int, ok bool) {
+	bits := make([]byte, 4)
+	binary.BigEndian.PutUint32(bits, ip)
+	ipBits := make([]int, 32)
+	for i := 0; i < 32; i++ {
+		ipBits[i] = int((bits[i/8] >> (7 - uint(i%8))) & 1)
+	}
+
+	node := 0
+	if idx.ipVersion == 6 {
+		// descend 96 bits of zero (::/96 prefix) before the IPv4
+		// bits take over, per the MaxMind DB spec's IPv4-in-IPv6 handling.
+		for i := 0; i < 96; i++ {
+			node = idx.child(node, 0)
+			if node >= idx.nodeCount {
+				return idx.resolve(node)
+			}
+		}
+	}
+
+	for _, b := range ipBits {
+		node = idx.child(node, b)
+		if node >= idx.nodeCount {
+			return idx.resolve(node)
+		}
+	}
+	return nil, 0, false
+}
+
+// child returns the left (bit==0) or right (bit==1) record of node. Most
+// MaxMind DB files use a 28-bit record size, which isn't byte-aligned: each
+// node is 7 bytes, with the left and right 24-bit halves sharing a middle
+// byte that contributes each record's high nibble. 24- and 32-bit record
+// sizes are cleanly byte-aligned and split evenly in half instead.
+func (idx *index) child(node, bit int) int {
+	if idx.recordSize == 28 {
+		b := idx.data[node*7 : node*7+7]
+		if bit == 0 {
+			return int(b[3]&0xf0)<<20 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		}
+		return int(b[3]&0x0f)<<24 | int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+	}
+
+	recBytes := idx.recordSize / 8
+	base := node * recBytes * 2
+	if bit == 1 {
+		base += recBytes
+	}
+	return int(beUint(idx.data[base : base+recBytes]))
+}
+
+// resolve returns the data section (sliced so it starts at offset 0) plus
+// the offset of node's record within it. Pointer values inside the data
+// section are themselves data-section-relative per the MaxMind DB spec, so
+// slicing here — rather than handing decode the whole file plus an
+// absolute offset — lets decodePointer treat every offset it sees,
+// top-level or pointed-to, the same way.
+func (idx *index) resolve(node int) (data []byte, offset int, ok bool) {
+	if node == idx.nodeCount {
+		return nil, 0, false // no data for this range
+	}
+	pointer := node - idx.nodeCount
+	if idx.dataOffset+pointer >= len(idx.data) {
+		return nil, 0, false
+	}
+	return idx.data[idx.dataOffset:], pointer, true
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// ParseRecord decodes the MaxMind data-section map at offset and pulls out
+// the handful of fields ip17mon.LocationInfo cares about. data is the data
+// section itself (see index.resolve), so offset and any pointers decode
+// encounters within it share the same data-section-relative base.
+func (parser) ParseRecord(data []byte, offset int) (*ip17mon.LocationInfo, error) {
+	v, _, err := decode(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errBadData
+	}
+
+	return &ip17mon.LocationInfo{
+		Country: isoOrName(m["country"]),
+		Region:  firstSubdivisionName(m["subdivisions"]),
+		City:    englishName(m["city"]),
+		Isp:     stringField(m["isp"]),
+	}, nil
+}
+
+func isoOrName(v interface{}) string {
+	m, _ := v.(map[string]interface{})
+	if m == nil {
+		return ""
+	}
+	if code, ok := m["iso_code"].(string); ok {
+		return code
+	}
+	return englishName(v)
+}
+
+func englishName(v interface{}) string {
+	m, _ := v.(map[string]interface{})
+	if m == nil {
+		return ""
+	}
+	names, _ := m["names"].(map[string]interface{})
+	if names == nil {
+		return ""
+	}
+	s, _ := names["en"].(string)
+	return s
+}
+
+func firstSubdivisionName(v interface{}) string {
+	arr, _ := v.([]interface{})
+	if len(arr) == 0 {
+		return ""
+	}
+	return englishName(arr[0])
+}
+
+func stringField(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
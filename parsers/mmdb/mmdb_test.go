@@ -0,0 +1,106 @@
+package mmdb
+
+import "testing"
+
+// TestLookupAndParseRecordWithPointer builds a full synthetic mmdb file —
+// a one-node tree, the 16-byte all-zero separator, a data section holding
+// a map whose "isp" value is pointer-compressed to a string elsewhere in
+// the data section, and a metadata map — and exercises Lookup+ParseRecord
+// end-to-end. This is the shape every real GeoLite2/GeoIP2 file takes for
+// deduplicated strings, and it's what catches a pointer resolved against
+// the wrong base offset (see index.resolve).
+func TestLookupAndParseRecordWithPointer(t *testing.T) {
+	var data []byte
+	put := func(b ...byte) int {
+		off := len(data)
+		data = append(data, b...)
+		return off
+	}
+	putStr := func(s string) int {
+		return put(append([]byte{0x40 | byte(len(s))}, s...)...)
+	}
+
+	// Tree: 1 node, 24-bit records, both children point to record value 2
+	// (pointer = 2 - nodeCount(1) = 1), a data pointer regardless of which
+	// bit is taken. A record value equal to nodeCount means "no data"; it
+	// must be strictly greater to resolve into the data section.
+	put(0x00, 0x00, 0x02, 0x00, 0x00, 0x02)
+	put(make([]byte, 16)...) // all-zero separator
+
+	dataOffset := len(data)
+	put(0x00) // 1 byte of padding: record value 2 resolves to data-section offset 1
+	// Data section: {"isp": <pointer to the string below>}.
+	put(0xe1) // map, size 1
+	putStr("isp")
+	strOff := len(data) + 2 // offset of the string, once the 2-byte pointer is in place
+	put(0x20, byte(strOff-dataOffset))
+	putStr("China Telecom")
+
+	metaOff := len(data) + len(metadataMarker)
+	put(metadataMarker...)
+	put(0xe3) // map, size 3
+	putStr("node_count")
+	put(0xc4, 0, 0, 0, 1) // uint32, size 4, value 1
+	putStr("record_size")
+	put(0xa2, 0, 24) // uint16, size 2, value 24
+	putStr("ip_version")
+	put(0xa2, 0, 4) // uint16, size 2, value 4
+
+	if got := findMetadata(data); got != metaOff {
+		t.Fatalf("findMetadata = %d, want %d", got, metaOff)
+	}
+
+	p := parser{}
+	if !p.Sniff(data) {
+		t.Fatal("Sniff returned false for well-formed data")
+	}
+	idxIface, err := p.ParseIndex(data)
+	if err != nil {
+		t.Fatalf("ParseIndex: %v", err)
+	}
+
+	recData, off, ok := idxIface.Lookup(0x01020304)
+	if !ok {
+		t.Fatal("Lookup = not ok")
+	}
+	info, err := p.ParseRecord(recData, off)
+	if err != nil {
+		t.Fatalf("ParseRecord: %v", err)
+	}
+	if info.Isp != "China Telecom" {
+		t.Errorf("Isp = %q, want %q (pointer resolved against the wrong base offset)", info.Isp, "China Telecom")
+	}
+}
+
+func TestChild28Bit(t *testing.T) {
+	// One node, 7 bytes: left = 0x0ABCDEF, right = 0x1234567 (28 bits each).
+	idx := &index{
+		recordSize: 28,
+		data:       []byte{0xAB, 0xCD, 0xEF, 0x01, 0x23, 0x45, 0x67},
+	}
+
+	if got, want := idx.child(0, 0), 0x0ABCDEF; got != want {
+		t.Errorf("left record = %#x, want %#x", got, want)
+	}
+	if got, want := idx.child(0, 1), 0x1234567; got != want {
+		t.Errorf("right record = %#x, want %#x", got, want)
+	}
+}
+
+func TestChild24And32Bit(t *testing.T) {
+	idx24 := &index{recordSize: 24, data: []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x02}}
+	if got, want := idx24.child(0, 0), 1; got != want {
+		t.Errorf("24-bit left = %d, want %d", got, want)
+	}
+	if got, want := idx24.child(0, 1), 2; got != want {
+		t.Errorf("24-bit right = %d, want %d", got, want)
+	}
+
+	idx32 := &index{recordSize: 32, data: []byte{0, 0, 0, 1, 0, 0, 0, 2}}
+	if got, want := idx32.child(0, 0), 1; got != want {
+		t.Errorf("32-bit left = %d, want %d", got, want)
+	}
+	if got, want := idx32.child(0, 1), 2; got != want {
+		t.Errorf("32-bit right = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+// Package qqwry parses the "qqwry.dat" pure IP database format (aka
+// "纯真IP库") and registers itself with ip17mon under the name "qqwry".
+package qqwry
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/xiaoqiang0/ip17mon"
+)
+
+func init() {
+	ip17mon.RegisterParser("qqwry", parser{})
+}
+
+var (
+	errBadRecord    = errors.New("qqwry: malformed record")
+	errRedirectLoop = errors.New("qqwry: redirect chain too deep")
+)
+
+// record modes, per the qqwry.dat spec.
+const (
+	modeRedirect1 = 0x01 // whole record redirected to another offset
+	modeRedirect2 = 0x02 // a single field (country or area) redirected
+)
+
+// maxRedirects bounds how many redirects readString/readRecord will follow
+// before giving up, so a corrupt file with a redirect cycle can't hang a
+// lookup.
+const maxRedirects = 8
+
+type parser struct{}
+
+// Sniff has no magic bytes to go on: qqwry.dat starts with two absolute
+// file offsets. We accept the format when those offsets are internally
+// consistent (ascending, 7-byte-aligned, within the file) since nothing
+// else distinguishes it from a truncated or unrelated file.
+func (parser) Sniff(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	start := binary.LittleEndian.Uint32(data[0:4])
+	end := binary.LittleEndian.Uint32(data[4:8])
+	return end > start && (end-start)%7 == 0 && int(end)+7 <= len(data)
+}
+
+type index struct {
+	data  []byte
+	start uint32
+	n     int
+}
+
+func (parser) ParseIndex(data []byte) (ip17mon.Index, error) {
+	start := binary.LittleEndian.Uint32(data[0:4])
+	end := binary.LittleEndian.Uint32(data[4:8])
+	return &index{data: data, start: start, n: int((end-start)/7) + 1}, nil
+}
+
+func (idx *index) entryIP(i int) uint32 {
+	off := idx.start + uint32(i)*7
+	return binary.LittleEndian.Uint32(idx.data[off : off+4])
+}
+
+func (idx *index) recordOffset(i int) uint32 {
+	off := idx.start + uint32(i)*7 + 4
+	return uint24(idx.data[off : off+3])
+}
+
+// Lookup binary searches the index for the range containing ip and
+// returns the whole file plus the offset of that range's record. Redirect
+// records (see ParseRecord) can point anywhere in the file, including
+// before the record's own offset, so ParseRecord needs the full buffer
+// rather than just a tail slice of it.
+func (idx *index) Lookup(ip uint32) (data []byte, offset int, ok bool) {
+	lo, hi := 0, idx.n-1
+	if ip < idx.entryIP(0) {
+		return nil, 0, false
+	}
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if idx.entryIP(mid) <= ip {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	recOff := idx.recordOffset(lo)
+	if int(recOff)+4 > len(idx.data) {
+		return nil, 0, false
+	}
+	return idx.data, int(recOff), true
+}
+
+// ParseRecord follows the mode-1/mode-2 redirects used by qqwry.dat and
+// returns a LocationInfo built from the country/area strings it finds.
+// qqwry has no ISP/city granularity distinct from country+area, so the
+// area string goes into Region, and City and Isp are left as the zero
+// value and filled with ip17mon.Null by the caller.
+func (parser) ParseRecord(data []byte, offset int) (*ip17mon.LocationInfo, error) {
+	if offset+4 > len(data) {
+		return nil, errBadRecord
+	}
+	offset += 4 // skip the end-of-range IP stored at the start of the record
+
+	country, area, err := readRecord(data, offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ip17mon.LocationInfo{Country: country, Region: area}, nil
+}
+
+// readRecord reads the country+area pair of the record at offset (past
+// its 4-byte end IP), calling itself on the record a mode-1 redirect
+// points at.
+func readRecord(data []byte, offset, depth int) (country, area string, err error) {
+	if depth >= maxRedirects {
+		return "", "", errRedirectLoop
+	}
+	if offset < 0 || offset >= len(data) {
+		return "", "", errBadRecord
+	}
+
+	switch data[offset] {
+	case modeRedirect1:
+		target := int(uint24(data[offset+1 : offset+4]))
+		return readRecord(data, target, depth+1)
+	case modeRedirect2:
+		country, err = readString(data, int(uint24(data[offset+1:offset+4])), depth+1)
+		if err != nil {
+			return "", "", err
+		}
+		area, err = readString(data, offset+4, depth+1)
+		return country, area, err
+	default:
+		var n int
+		country, n = readCString(data[offset:])
+		area, err = readString(data, offset+n, depth+1)
+		return country, area, err
+	}
+}
+
+// readString reads a single string field at offset, following mode-1/mode-2
+// redirects (up to maxRedirects deep) to reach the actual characters.
+func readString(data []byte, offset, depth int) (string, error) {
+	if depth >= maxRedirects {
+		return "", errRedirectLoop
+	}
+	if offset < 0 || offset >= len(data) {
+		return "", errBadRecord
+	}
+
+	switch data[offset] {
+	case modeRedirect1, modeRedirect2:
+		if offset+4 > len(data) {
+			return "", errBadRecord
+		}
+		target := int(uint24(data[offset+1 : offset+4]))
+		return readString(data, target, depth+1)
+	default:
+		s, _ := readCString(data[offset:])
+		return s, nil
+	}
+}
+
+func readCString(b []byte) (string, int) {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i]), i + 1
+		}
+	}
+	return string(b), len(b)
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
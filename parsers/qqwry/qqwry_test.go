@@ -0,0 +1,60 @@
+package qqwry
+
+import "testing"
+
+// TestParseRecordRedirects builds a tiny synthetic record layout covering
+// all three record shapes qqwry.dat actually uses: inline country+area, a
+// mode-2 country redirect with an inline area, and a mode-1 whole-record
+// redirect into a mode-2 record — the shape most real records take, since
+// country strings are deduplicated into a shared pool.
+func TestParseRecordRedirects(t *testing.T) {
+	data := []byte{}
+	put := func(b ...byte) int {
+		off := len(data)
+		data = append(data, b...)
+		return off
+	}
+
+	// Shared country string pool.
+	chinaOff := put(append([]byte("China"), 0)...)
+
+	// Record A: inline country + inline area.
+	recA := put(0, 0, 0, 0) // 4-byte end-IP placeholder, unused by ParseRecord
+	put(append([]byte("USA"), 0)...)
+	put(append([]byte("Nevada"), 0)...)
+
+	// Record B: mode-2 country redirect (-> chinaOff) + inline area.
+	recB := put(0, 0, 0, 0)
+	put(modeRedirect2, byte(chinaOff), byte(chinaOff>>8), byte(chinaOff>>16))
+	put(append([]byte("Beijing"), 0)...)
+
+	// Record C: mode-1 whole-record redirect -> Record B's content (past
+	// its own 4-byte end-IP field, which a redirect target never carries).
+	recBContent := recB + 4
+	recC := put(0, 0, 0, 0)
+	put(modeRedirect1, byte(recBContent), byte(recBContent>>8), byte(recBContent>>16))
+
+	cases := []struct {
+		name          string
+		offset        int
+		country, area string
+	}{
+		{"inline", recA, "USA", "Nevada"},
+		{"mode2-country-redirect", recB, "China", "Beijing"},
+		{"mode1-whole-record-redirect", recC, "China", "Beijing"},
+	}
+
+	p := parser{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info, err := p.ParseRecord(data, c.offset)
+			if err != nil {
+				t.Fatalf("ParseRecord: %v", err)
+			}
+			if info.Country != c.country || info.Region != c.area {
+				t.Errorf("got country=%q region=%q, want country=%q region=%q",
+					info.Country, info.Region, c.country, c.area)
+			}
+		})
+	}
+}
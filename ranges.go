@@ -0,0 +1,101 @@
+package ip17mon
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net"
+)
+
+// Ranges calls fn once for every contiguous IPv4 range in the loaded
+// database, in ascending order, stopping early if fn returns false. Each
+// range's start is derived from the previous range's end (the database
+// only stores each range's inclusive end), so ranges are always adjacent
+// and cover 0.0.0.0-255.255.255.255 in full.
+//
+// Ranges only supports Locators loaded through the built-in 17monipdb
+// format; it is a no-op for Parser-backed Locators (see parser.go), whose
+// Index does not expose enumeration.
+func (loc *Locator) Ranges(fn func(start, end uint32, info *LocationInfo) bool) {
+	if loc.idx != nil {
+		return
+	}
+
+	var start uint32
+	for i := 0; i < loc.nidx; i++ {
+		end := loc.indexIP(i)
+		off := loc.indexOffset(i)
+		info := newLocationInfo(loc.textData[off : off+loc.indexLen(i)])
+		if !fn(start, end, info) {
+			return
+		}
+		start = end + 1
+	}
+}
+
+// CIDRsFor returns the minimal set of CIDR blocks covering every range
+// whose LocationInfo matches filter, e.g. for firewall/ACL generation:
+//
+//	loc.CIDRsFor(func(info *ip17mon.LocationInfo) bool {
+//		return info.Country == "CN" && info.Isp == "电信"
+//	})
+//
+// Adjacent matching ranges (e.g. several ISPs' ranges within one country)
+// are coalesced into a single span before being decomposed into CIDRs, so
+// a country split across many raw database ranges still yields the
+// minimal block list rather than one set of blocks per raw range.
+func (loc *Locator) CIDRsFor(filter func(*LocationInfo) bool) []*net.IPNet {
+	var nets []*net.IPNet
+	var pending bool
+	var spanStart, spanEnd uint32
+
+	flush := func() {
+		if pending {
+			nets = append(nets, uint32RangeToCIDRs(spanStart, spanEnd)...)
+			pending = false
+		}
+	}
+
+	loc.Ranges(func(start, end uint32, info *LocationInfo) bool {
+		if !filter(info) {
+			flush()
+			return true
+		}
+		if pending {
+			spanEnd = end
+		} else {
+			pending, spanStart, spanEnd = true, start, end
+		}
+		return true
+	})
+	flush()
+
+	return nets
+}
+
+// uint32RangeToCIDRs decomposes the inclusive range [start, end] into the
+// minimal list of aligned CIDR blocks, repeatedly emitting the largest
+// block that starts at the current address without overshooting end.
+func uint32RangeToCIDRs(start, end uint32) []*net.IPNet {
+	var out []*net.IPNet
+	for {
+		count := uint64(end) - uint64(start) + 1
+		hostBits := bits.Len64(count) - 1 // floor(log2(count))
+		if start != 0 {
+			if tz := bits.TrailingZeros32(start); tz < hostBits {
+				hostBits = tz
+			}
+		}
+
+		blockSize := uint64(1) << uint(hostBits)
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start)
+		out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(32-hostBits, 32)})
+
+		next := uint64(start) + blockSize
+		if next > uint64(end) {
+			break
+		}
+		start = uint32(next)
+	}
+	return out
+}
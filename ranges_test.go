@@ -0,0 +1,79 @@
+package ip17mon
+
+import (
+	"testing"
+)
+
+// TestUint32RangeToCIDRs checks range decomposition against known cases:
+// a single aligned /24, a single host, and a range that isn't aligned to
+// any one block and so must split into several.
+func TestUint32RangeToCIDRs(t *testing.T) {
+	cases := []struct {
+		name       string
+		start, end uint32
+		wantCIDRs  []string
+	}{
+		{"aligned /24", 0x0A000000, 0x0A0000FF, []string{"10.0.0.0/24"}},
+		{"single host", 0x0A000005, 0x0A000005, []string{"10.0.0.5/32"}},
+		{
+			"unaligned range",
+			0x0A000001, 0x0A000006,
+			[]string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nets := uint32RangeToCIDRs(c.start, c.end)
+			if len(nets) != len(c.wantCIDRs) {
+				t.Fatalf("got %d CIDRs, want %d: %v", len(nets), len(c.wantCIDRs), nets)
+			}
+			for i, n := range nets {
+				if got := n.String(); got != c.wantCIDRs[i] {
+					t.Errorf("CIDR[%d] = %s, want %s", i, got, c.wantCIDRs[i])
+				}
+			}
+		})
+	}
+}
+
+// TestCIDRsForMergesAdjacentRanges builds a Locator with three adjacent
+// raw ranges, the first two matching the filter, to verify CIDRsFor
+// coalesces them into one minimal span instead of decomposing each raw
+// range independently.
+func TestCIDRsForMergesAdjacentRanges(t *testing.T) {
+	entries := []struct {
+		end     uint32
+		country string
+	}{
+		{127, "CN"},
+		{255, "CN"},
+		{511, "US"},
+	}
+
+	var textData []byte
+	var rawIndex []byte
+	for _, e := range entries {
+		off := len(textData)
+		rec := []byte(e.country + "\tRegion\tCity\t")
+		textData = append(textData, rec...)
+		rawIndex = append(rawIndex,
+			byte(e.end>>24), byte(e.end>>16), byte(e.end>>8), byte(e.end),
+			byte(off), byte(off>>8), byte(off>>16),
+			byte(len(rec)),
+		)
+	}
+
+	loc := &Locator{rawIndex: rawIndex, nidx: len(entries), textData: textData}
+	nets := loc.CIDRsFor(func(info *LocationInfo) bool { return info.Country == "CN" })
+
+	want := []string{"0.0.0.0/24"}
+	if len(nets) != len(want) {
+		t.Fatalf("got %d CIDRs %v, want %v", len(nets), nets, want)
+	}
+	for i, n := range nets {
+		if got := n.String(); got != want[i] {
+			t.Errorf("CIDR[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
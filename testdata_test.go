@@ -0,0 +1,29 @@
+package ip17mon
+
+// buildLocatorFile returns a minimal well-formed 17monipdb-format buffer
+// with a single record, covering the whole IPv4 range, whose LocationInfo
+// is the free-version "country\tregion\tcity" shape. It's shared by tests
+// that need a real on-disk-shaped Locator (mmap, Reload/Watch) rather than
+// a struct literal built straight from Locator's unexported fields.
+func buildLocatorFile(country string) []byte {
+	rec := []byte(country + "\tRegion\tCity\t")
+
+	const nidx = 1
+	textoff := 4 + 1024 + 1024 + nidx*8
+
+	data := make([]byte, textoff-1024+len(rec))
+	data[0], data[1], data[2], data[3] = byte(textoff>>24), byte(textoff>>16), byte(textoff>>8), byte(textoff)
+	// index (256 entries, [4:1028]) is left all-zero: with only one rawIndex
+	// entry, every octet's search starts and ends at it.
+
+	rawIndexOff := 4 + 1024
+	data[rawIndexOff+0] = 0xFF // end ip = 0xFFFFFFFF: this one record covers
+	data[rawIndexOff+1] = 0xFF // every address
+	data[rawIndexOff+2] = 0xFF
+	data[rawIndexOff+3] = 0xFF
+	// text offset (3-byte LE) = 0, text length = len(rec)
+	data[rawIndexOff+7] = byte(len(rec))
+
+	copy(data[textoff-1024:], rec)
+	return data
+}
@@ -0,0 +1,66 @@
+package ip17mon
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce bounds how long Watch waits after seeing an fsnotify event
+// for path before reloading, so an editor or log rotator's rename+truncate
+// write pattern triggers a single Reload instead of one per syscall.
+const watchDebounce = 200 * time.Millisecond
+
+// Watch starts a background goroutine that calls Reload(path) whenever
+// path is rewritten on disk, and runs until the process exits (there is no
+// way to stop it short of that, matching Init/Reload's existing
+// fire-and-forget style). Errors from the triggered Reload are swallowed:
+// a failed reload keeps the current Locator and does not call the hook set
+// by SetReloadHook, which only runs after successful reloads.
+func Watch(path string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// Watch the containing directory rather than path itself: editors and
+	// log rotators commonly replace a file via rename, which most platforms
+	// report against the directory, not a file handle that no longer
+	// points at the new inode.
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		w.Close()
+		return err
+	}
+
+	target := filepath.Clean(path)
+	go func() {
+		defer w.Close()
+
+		var debounce *time.Timer
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != target {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if debounce == nil {
+					debounce = time.AfterFunc(watchDebounce, func() { Reload(path) })
+				} else {
+					debounce.Reset(watchDebounce)
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
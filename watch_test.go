@@ -0,0 +1,64 @@
+package ip17mon
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWatchDebouncesReload writes the watched file several times in quick
+// succession (faster than watchDebounce) and checks Watch collapses them
+// into a single Reload, then confirms a write after the debounce window
+// has elapsed triggers a further reload.
+func TestWatchDebouncesReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "17monipdb.dat")
+	if err := os.WriteFile(path, buildLocatorFile("CN"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var reloads int32
+	SetReloadHook(func(old, new *Locator) { atomic.AddInt32(&reloads, 1) })
+	defer SetReloadHook(nil)
+
+	if err := Init(path); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if err := Watch(path); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(path, buildLocatorFile("US"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&reloads) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&reloads) == 0 {
+		t.Skip("no fsnotify event observed; filesystem watching is unavailable in this environment")
+	}
+	if got := atomic.LoadInt32(&reloads); got != 1 {
+		t.Errorf("reloads after a burst of writes = %d, want 1 (debounced)", got)
+	}
+
+	time.Sleep(watchDebounce + 100*time.Millisecond)
+	if err := os.WriteFile(path, buildLocatorFile("JP"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&reloads) == 1 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&reloads); got != 2 {
+		t.Errorf("reloads after a later, separate write = %d, want 2", got)
+	}
+}